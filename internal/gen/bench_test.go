@@ -0,0 +1,157 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 Hajime Hoshi
+
+package gen_test
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/net/html"
+
+	"github.com/hajimehoshi/hajimehoshi.com/internal/gen"
+)
+
+var update = flag.Bool("update", false, "update .golden files in testdata")
+
+// corpusFiles returns the full-article HTML fixtures under testdata used by
+// both the benchmarks and TestGolden below, in the style of Hugo's bench
+// harness: a handful of realistic pages instead of synthetic snippets.
+func corpusFiles(tb testing.TB) []string {
+	tb.Helper()
+
+	entries, err := os.ReadDir("testdata")
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	var paths []string
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".html" {
+			continue
+		}
+		paths = append(paths, filepath.Join("testdata", e.Name()))
+	}
+	if len(paths) == 0 {
+		tb.Fatal("no corpus files found under testdata")
+	}
+	return paths
+}
+
+func parseCorpusFile(tb testing.TB, path string) *html.Node {
+	tb.Helper()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	nodes, err := html.ParseFragment(bytes.NewReader(b), nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return nodes[0]
+}
+
+func renderNode(tb testing.TB, node *html.Node) string {
+	tb.Helper()
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, node); err != nil {
+		tb.Fatal(err)
+	}
+	return buf.String()
+}
+
+func thinSpaceSpacer() gen.SpacingStrategy {
+	return gen.ElementSpacer{
+		Template: &html.Node{
+			Type: html.ElementNode,
+			Data: "span",
+			Attr: []html.Attribute{{Key: "class", Val: "thin-space"}},
+		},
+	}
+}
+
+func BenchmarkRemoveInterElementWhitespace(b *testing.B) {
+	paths := corpusFiles(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		node := parseCorpusFile(b, paths[i%len(paths)])
+		b.StartTimer()
+
+		gen.RemoveInterElementWhitespace(node)
+	}
+}
+
+func BenchmarkProcessNewLines(b *testing.B) {
+	paths := corpusFiles(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		node := parseCorpusFile(b, paths[i%len(paths)])
+		gen.RemoveInterElementWhitespace(node)
+		b.StartTimer()
+
+		gen.ProcessNewLines(node)
+	}
+}
+
+func BenchmarkInsertNodeBetweenWideAndNarrow(b *testing.B) {
+	paths := corpusFiles(b)
+	spacer := thinSpaceSpacer()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		node := parseCorpusFile(b, paths[i%len(paths)])
+		gen.RemoveInterElementWhitespace(node)
+		gen.ProcessNewLines(node)
+		b.StartTimer()
+
+		gen.InsertNodeBetweenWideAndNarrow(node, spacer)
+	}
+}
+
+// TestGolden runs every testdata/*.html fixture through the same
+// whitespace/spacing pipeline finalizeHTML applies to a real page, and
+// compares the result against testdata/<name>.golden. Run with -update to
+// regenerate the golden files after an intentional change.
+//
+// The fixtures deliberately contain several wide/narrow boundaries inside a
+// single text run (e.g. "HTML生成パイプラインをGoで書き直しました"), which exercises
+// InsertNodeBetweenWideAndNarrow's multi-token splitting path end to end,
+// not just the single-boundary cases in TestInsertNodeBetweenWideAndNarrow.
+func TestGolden(t *testing.T) {
+	for _, path := range corpusFiles(t) {
+		path := path
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			node := parseCorpusFile(t, path)
+			gen.RemoveInterElementWhitespace(node)
+			gen.ProcessNewLines(node)
+			gen.InsertNodeBetweenWideAndNarrow(node, thinSpaceSpacer())
+			got := renderNode(t, node)
+
+			goldenPath := path + ".golden"
+			if *update {
+				if err := os.WriteFile(goldenPath, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("golden mismatch for %s; run with -update to regenerate\ngot:\n%s\nwant:\n%s", path, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 Hajime Hoshi
+
+package gen
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// fileIntegrity computes a Subresource Integrity value
+// (https://www.w3.org/TR/SRI/) for the file at path, using SHA-384, the
+// algorithm browsers currently recommend.
+func fileIntegrity(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return inlineIntegrity(b), nil
+}
+
+// inlineIntegrity computes the SRI value for inline content, suitable for
+// use in a CSP hash-source (e.g. 'sha384-...').
+func inlineIntegrity(b []byte) string {
+	sum := sha512.Sum384(b)
+	return "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// cspEntry is the per-page set of directives generated while finalizing a
+// page, recorded so generateHeadersSidecar can emit outDir/_headers for the
+// dev server to apply as real HTTP response headers.
+type cspEntry struct {
+	Path   string // relative to outDir, e.g. "en/index.html"
+	Policy string
+}
+
+var (
+	cspEntriesM sync.Mutex
+	// cspEntries is keyed by Path so that RunIncremental, which regenerates
+	// one page at a time without starting from a blank slate, replaces that
+	// page's entry instead of appending a duplicate every time it is
+	// rebuilt.
+	cspEntries = map[string]cspEntry{}
+)
+
+func recordCSP(path, policy string) {
+	cspEntriesM.Lock()
+	defer cspEntriesM.Unlock()
+	cspEntries[path] = cspEntry{Path: path, Policy: policy}
+}
+
+// resetCSPEntries discards every previously recorded CSP entry, so a full
+// Run doesn't carry forward headers for pages that have since been removed
+// from inDir.
+func resetCSPEntries() {
+	cspEntriesM.Lock()
+	defer cspEntriesM.Unlock()
+	cspEntries = map[string]cspEntry{}
+}
+
+// buildCSP assembles a Content-Security-Policy value whose script-src and
+// style-src directives enumerate exactly the hashes of the inline
+// style/script blocks generateHTML produced for this page, plus 'self' for
+// same-origin external files.
+func buildCSP(styleHashes, scriptHashes []string) string {
+	styleSrc := append([]string{"'self'"}, quoted(styleHashes)...)
+	scriptSrc := append([]string{"'self'"}, quoted(scriptHashes)...)
+	return fmt.Sprintf("style-src %s; script-src %s", strings.Join(styleSrc, " "), strings.Join(scriptSrc, " "))
+}
+
+func quoted(hashes []string) []string {
+	out := make([]string, len(hashes))
+	for i, h := range hashes {
+		out[i] = "'" + h + "'"
+	}
+	return out
+}
+
+// generateHeadersSidecar writes outDir/_headers, a sidecar file listing the
+// Content-Security-Policy recorded for every generated page. The dev server
+// (server.go) reads it back to apply real HTTP headers during local preview.
+func generateHeadersSidecar(outDir string) error {
+	cspEntriesM.Lock()
+	entries := make([]cspEntry, 0, len(cspEntries))
+	for _, e := range cspEntries {
+		entries = append(entries, e)
+	}
+	cspEntriesM.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+
+	f, err := os.Create(filepath.Join(outDir, "_headers"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	for _, e := range entries {
+		if _, err := fmt.Fprintf(f, "/%s\n  Content-Security-Policy: %s\n", e.Path, e.Policy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 Hajime Hoshi
+
+package gen_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hajimehoshi/hajimehoshi.com/internal/gen"
+)
+
+func TestInlineIntegrity(t *testing.T) {
+	got := gen.InlineIntegrity([]byte("body { color: red; }"))
+	if !strings.HasPrefix(got, "sha384-") {
+		t.Errorf("got %q, want it to start with %q", got, "sha384-")
+	}
+	if got2 := gen.InlineIntegrity([]byte("body { color: red; }")); got2 != got {
+		t.Errorf("inlineIntegrity is not deterministic: %q != %q", got, got2)
+	}
+	if got3 := gen.InlineIntegrity([]byte("body { color: blue; }")); got3 == got {
+		t.Errorf("inlineIntegrity returned the same hash for different content")
+	}
+}
+
+func TestBuildCSP(t *testing.T) {
+	got := gen.BuildCSP([]string{"sha384-aaa"}, []string{"sha384-bbb", "sha384-ccc"})
+	want := "style-src 'self' 'sha384-aaa'; script-src 'self' 'sha384-bbb' 'sha384-ccc'"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestGenerateHeadersSidecar(t *testing.T) {
+	gen.ResetCSPEntries()
+	t.Cleanup(gen.ResetCSPEntries)
+
+	gen.RecordCSP("en/index.html", "style-src 'self'")
+	gen.RecordCSP("en/blog/post.html", "style-src 'self' 'sha384-aaa'")
+	// A later re-record for the same path (as RunIncremental does when a
+	// page is rebuilt) should replace, not duplicate, the earlier entry.
+	gen.RecordCSP("en/index.html", "style-src 'self' 'sha384-zzz'")
+
+	outDir := t.TempDir()
+	if err := gen.GenerateHeadersSidecar(outDir); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(outDir, "_headers"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(b)
+
+	want := "/en/blog/post.html\n  Content-Security-Policy: style-src 'self' 'sha384-aaa'\n" +
+		"/en/index.html\n  Content-Security-Policy: style-src 'self' 'sha384-zzz'\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
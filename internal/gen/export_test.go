@@ -15,6 +15,83 @@ func ProcessNewLines(node *html.Node) {
 	processNewLines(node)
 }
 
-func InsertNodeBetweenWideAndNarrow(node *html.Node, insertingNode *html.Node) {
-	insertNodeBetweenWideAndNarrow(node, insertingNode)
+func InsertNodeBetweenWideAndNarrow(node *html.Node, strategy SpacingStrategy) {
+	insertNodeBetweenWideAndNarrow(node, strategy)
+}
+
+// FrontMatter is frontMatter, exported for tests.
+type FrontMatter = frontMatter
+
+func SplitFrontMatter(src []byte) (FrontMatter, []byte, error) {
+	return splitFrontMatter(src)
+}
+
+// FeedItem is feedItem, exported for tests.
+type FeedItem = feedItem
+
+func RecordFeedItem(item FeedItem) {
+	recordFeedItem(item)
+}
+
+func ResetFeedItems() {
+	resetFeedItems()
+}
+
+func GenerateFeeds(outDir string) error {
+	return generateFeeds(outDir)
+}
+
+// BuildCache is buildCache, exported for tests.
+type BuildCache = buildCache
+
+func LoadBuildCache(outDir string) (*BuildCache, error) {
+	return loadBuildCache(outDir)
+}
+
+func (c *BuildCache) Save(outDir string) error {
+	return c.save(outDir)
+}
+
+func (c *BuildCache) UpToDate(outPath string, inputs []string) (bool, error) {
+	return c.upToDate(outPath, inputs)
+}
+
+func (c *BuildCache) Record(outPath string, inputs []string) error {
+	return c.record(outPath, inputs)
+}
+
+// ImageVariant is imageVariant, exported for tests.
+type ImageVariant = imageVariant
+
+// ImageManifestEntry is imageManifestEntry, exported for tests.
+type ImageManifestEntry = imageManifestEntry
+
+func SetImageManifestEntry(path string, entry ImageManifestEntry) {
+	imageManifestM.Lock()
+	defer imageManifestM.Unlock()
+	imageManifest[path] = entry
+}
+
+func TransformImages(node *html.Node) {
+	transformImages(node)
+}
+
+func InlineIntegrity(b []byte) string {
+	return inlineIntegrity(b)
+}
+
+func BuildCSP(styleHashes, scriptHashes []string) string {
+	return buildCSP(styleHashes, scriptHashes)
+}
+
+func RecordCSP(path, policy string) {
+	recordCSP(path, policy)
+}
+
+func ResetCSPEntries() {
+	resetCSPEntries()
+}
+
+func GenerateHeadersSidecar(outDir string) error {
+	return generateHeadersSidecar(outDir)
 }
@@ -0,0 +1,228 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 Hajime Hoshi
+
+package gen
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+// siteBaseURL is the canonical origin feed entries are linked against.
+const siteBaseURL = "https://hajimehoshi.com"
+
+// feedScope reports whether path, relative to contents/, falls under a
+// language's blog subtree (e.g. "en/blog/foo.html"), and if so returns the
+// language.
+func feedScope(path string) (lang string, ok bool) {
+	ts := strings.Split(path, string(filepath.Separator))
+	if len(ts) < 2 || ts[1] != "blog" {
+		return "", false
+	}
+	return ts[0], true
+}
+
+// feedItem is a single entry collected while generating HTML, destined for
+// that language's feed.xml and atom.xml.
+type feedItem struct {
+	Lang    string
+	Title   string
+	Path    string // relative to contents/, e.g. "en/blog/foo.html"
+	Date    string
+	Summary string
+}
+
+func (f feedItem) url() string {
+	p := strings.TrimSuffix(f.Path, filepath.Ext(f.Path)) + ".html"
+	return siteBaseURL + "/" + filepath.ToSlash(p)
+}
+
+var (
+	feedItemsM sync.Mutex
+	// feedItems is keyed by Path so that RunIncremental, which regenerates
+	// one page at a time without starting from a blank slate, replaces that
+	// page's entry instead of appending a duplicate every time it is
+	// rebuilt.
+	feedItems = map[string]feedItem{}
+)
+
+func recordFeedItem(item feedItem) {
+	feedItemsM.Lock()
+	defer feedItemsM.Unlock()
+	feedItems[item.Path] = item
+}
+
+// resetFeedItems discards every previously recorded feed item, so a full Run
+// doesn't carry forward entries for pages that have since been removed from
+// inDir.
+func resetFeedItems() {
+	feedItemsM.Lock()
+	defer feedItemsM.Unlock()
+	feedItems = map[string]feedItem{}
+}
+
+// firstParagraphAfterH1 returns the text content of the first <p> element
+// that follows the document's <h1> in document order, for use as a feed
+// entry summary. It returns "" if there is no h1 or no following p.
+func firstParagraphAfterH1(htmle *html.Node) string {
+	h1 := getElementByName(htmle, "h1")
+	if h1 == nil {
+		return ""
+	}
+
+	var found bool
+	var p *html.Node
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if p != nil {
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			if c == h1 {
+				found = true
+				continue
+			}
+			if found && c.Data == "p" {
+				p = c
+				return
+			}
+			walk(c)
+			if p != nil {
+				return
+			}
+		}
+	}
+	walk(htmle)
+	if p == nil {
+		return ""
+	}
+	summary, err := RenderPlainText(p, Options{})
+	if err != nil {
+		return ""
+	}
+	return summary
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title string    `xml:"title"`
+	Link  string    `xml:"link"`
+	Items []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	PubDate     string `xml:"pubDate,omitempty"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	Title   string   `xml:"title"`
+	Link    atomLink `xml:"link"`
+	Summary string   `xml:"summary"`
+	Updated string   `xml:"updated,omitempty"`
+}
+
+// generateFeeds writes an RSS 2.0 feed.xml and an Atom atom.xml into each
+// language's blog directory under outDir, based on the feed items recorded
+// while generating HTML.
+func generateFeeds(outDir string) error {
+	feedItemsM.Lock()
+	items := make([]feedItem, 0, len(feedItems))
+	for _, item := range feedItems {
+		items = append(items, item)
+	}
+	feedItemsM.Unlock()
+
+	byLang := map[string][]feedItem{}
+	for _, item := range items {
+		byLang[item.Lang] = append(byLang[item.Lang], item)
+	}
+
+	for lang, items := range byLang {
+		sort.Slice(items, func(i, j int) bool {
+			return items[i].Date > items[j].Date
+		})
+
+		dir := filepath.Join(outDir, lang, "blog")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+
+		rss := rssFeed{
+			Version: "2.0",
+			Channel: rssChannel{
+				Title: "hajimehoshi.com",
+				Link:  fmt.Sprintf("%s/%s/blog/", siteBaseURL, lang),
+			},
+		}
+		atom := atomFeed{
+			Title: "hajimehoshi.com",
+			Link:  atomLink{Href: fmt.Sprintf("%s/%s/blog/", siteBaseURL, lang)},
+		}
+		for _, item := range items {
+			rss.Channel.Items = append(rss.Channel.Items, rssItem{
+				Title:       item.Title,
+				Link:        item.url(),
+				Description: item.Summary,
+				PubDate:     item.Date,
+			})
+			atom.Entries = append(atom.Entries, atomEntry{
+				Title:   item.Title,
+				Link:    atomLink{Href: item.url()},
+				Summary: item.Summary,
+				Updated: item.Date,
+			})
+		}
+
+		if err := writeXML(filepath.Join(dir, "feed.xml"), rss); err != nil {
+			return err
+		}
+		if err := writeXML(filepath.Join(dir, "atom.xml"), atom); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeXML(path string, v any) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(xml.Header); err != nil {
+		return err
+	}
+	e := xml.NewEncoder(f)
+	e.Indent("", "  ")
+	return e.Encode(v)
+}
@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 Hajime Hoshi
+
+package gen_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hajimehoshi/hajimehoshi.com/internal/gen"
+)
+
+func TestGenerateFeeds(t *testing.T) {
+	gen.ResetFeedItems()
+	t.Cleanup(gen.ResetFeedItems)
+
+	gen.RecordFeedItem(gen.FeedItem{
+		Lang:    "en",
+		Title:   "Older post",
+		Path:    "en/blog/older.html",
+		Date:    "2023-01-01",
+		Summary: "An older post.",
+	})
+	gen.RecordFeedItem(gen.FeedItem{
+		Lang:    "en",
+		Title:   "Newer post",
+		Path:    "en/blog/newer.html",
+		Date:    "2023-06-01",
+		Summary: "A newer post.",
+	})
+
+	outDir := t.TempDir()
+	if err := gen.GenerateFeeds(outDir); err != nil {
+		t.Fatal(err)
+	}
+
+	rss, err := os.ReadFile(filepath.Join(outDir, "en", "blog", "feed.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Index(string(rss), "Newer post"), strings.Index(string(rss), "Older post"); got == -1 || want == -1 || got > want {
+		t.Errorf("feed.xml did not list items newest first:\n%s", rss)
+	}
+	if !strings.Contains(string(rss), "https://hajimehoshi.com/en/blog/newer.html") {
+		t.Errorf("feed.xml missing expected item link:\n%s", rss)
+	}
+
+	atom, err := os.ReadFile(filepath.Join(outDir, "en", "blog", "atom.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(atom), "<feed") || !strings.Contains(string(atom), "Newer post") {
+		t.Errorf("atom.xml missing expected entry:\n%s", atom)
+	}
+}
+
+func TestGenerateFeedsReplacesOnRerecord(t *testing.T) {
+	gen.ResetFeedItems()
+	t.Cleanup(gen.ResetFeedItems)
+
+	gen.RecordFeedItem(gen.FeedItem{Lang: "en", Title: "v1", Path: "en/blog/post.html", Date: "2023-01-01"})
+	gen.RecordFeedItem(gen.FeedItem{Lang: "en", Title: "v2", Path: "en/blog/post.html", Date: "2023-01-01"})
+
+	outDir := t.TempDir()
+	if err := gen.GenerateFeeds(outDir); err != nil {
+		t.Fatal(err)
+	}
+
+	rss, err := os.ReadFile(filepath.Join(outDir, "en", "blog", "feed.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(rss), "v1") {
+		t.Errorf("feed.xml still contains the stale entry:\n%s", rss)
+	}
+	if strings.Count(string(rss), "<item>") != 1 {
+		t.Errorf("feed.xml has %d <item> entries for the same Path, want 1:\n%s", strings.Count(string(rss), "<item>"), rss)
+	}
+}
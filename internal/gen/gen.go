@@ -80,13 +80,113 @@ func Run() error {
 	if err := os.RemoveAll(outDir); err != nil {
 		return err
 	}
+	resetFeedItems()
+	resetCSPEntries()
 	if err := copyNonHTMLFiles(outDir, inDir); err != nil {
 		return err
 	}
 	if err := generateHTMLs(outDir, inDir); err != nil {
 		return err
 	}
-	return nil
+	if err := generateFeeds(outDir); err != nil {
+		return err
+	}
+	if err := generateHeadersSidecar(outDir); err != nil {
+		return err
+	}
+	return writeBuildCache(outDir, inDir)
+}
+
+// RunIncremental rebuilds only the outputs affected by changed, a list of
+// paths (relative to the working directory) that have changed on disk since
+// the last Run or RunIncremental. It is intended for the dev server, which
+// calls it on every file-system notification instead of paying for a full
+// cold rebuild.
+//
+// If outDir does not yet have a build cache (e.g. this is the first call),
+// RunIncremental falls back to a full Run.
+func RunIncremental(changed []string) error {
+	const (
+		outDir = "_site"
+		inDir  = "contents"
+	)
+
+	cache, err := loadBuildCache(outDir)
+	if err != nil {
+		return err
+	}
+	if len(cache.Entries) == 0 {
+		return Run()
+	}
+
+	// sharedDeps are keyed by their path under inDir, since that is what
+	// changed (passed in by the dev server's fsnotify watch on contents/)
+	// actually contains; watchContents never reports a path under outDir.
+	sharedDeps := map[string]bool{
+		filepath.Join(inDir, "style.css"): true,
+		filepath.Join(inDir, "script.js"): true,
+	}
+
+	var rebuildAll bool
+	var toRebuild []string
+	for _, path := range changed {
+		forgetFileHash(path)
+
+		if sharedDeps[path] {
+			rebuildAll = true
+			continue
+		}
+		if rel, err := filepath.Rel(inDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			if filepath.Ext(rel) == ".html" || filepath.Ext(rel) == ".md" {
+				toRebuild = append(toRebuild, rel)
+				continue
+			}
+			// A non-HTML content file (image, CSS partial, etc.) changed;
+			// re-copy it and rebuild everything that might reference it.
+			if err := copyNonHTMLFiles(outDir, inDir); err != nil {
+				return err
+			}
+			rebuildAll = true
+		}
+	}
+
+	if rebuildAll {
+		return Run()
+	}
+
+	for _, rel := range toRebuild {
+		inputs := []string{
+			filepath.Join(inDir, rel),
+			filepath.Join(outDir, "style.css"),
+			filepath.Join(outDir, "script.js"),
+		}
+		if upToDate, err := cache.upToDate(rel, inputs); err != nil {
+			return err
+		} else if upToDate {
+			continue
+		}
+
+		var err error
+		switch filepath.Ext(rel) {
+		case ".html":
+			err = generateHTML(rel, outDir, inDir)
+		case ".md":
+			err = generateMarkdown(rel, outDir, inDir)
+		}
+		if err != nil {
+			return err
+		}
+		if err := recordBuildCacheEntry(cache, rel, outDir); err != nil {
+			return err
+		}
+	}
+	if err := generateFeeds(outDir); err != nil {
+		return err
+	}
+	if err := generateHeadersSidecar(outDir); err != nil {
+		return err
+	}
+	return cache.save(outDir)
 }
 
 func isIgnoredFile(path string) bool {
@@ -111,7 +211,8 @@ func copyNonHTMLFiles(outDir, inDir string) error {
 		if info.IsDir() {
 			return nil
 		}
-		if filepath.Ext(path) == ".html" {
+		switch filepath.Ext(path) {
+		case ".html", ".md":
 			return nil
 		}
 		if isIgnoredFile(path) {
@@ -161,6 +262,15 @@ func copyNonHTMLFiles(outDir, inDir string) error {
 					return err
 				}
 			}
+
+			if isImageFile(path) {
+				if err := out.Close(); err != nil {
+					return err
+				}
+				if err := generateImageVariants(outDir, inRelPath, outPath); err != nil {
+					return err
+				}
+			}
 			return nil
 		})
 		return nil
@@ -182,20 +292,28 @@ func generateHTMLs(outDir, inDir string) error {
 		if info.IsDir() {
 			return nil
 		}
-		if filepath.Ext(path) != ".html" {
-			return nil
-		}
 		if isIgnoredFile(path) {
 			return nil
 		}
-		path, err = filepath.Rel(inDir, path)
-		if err != nil {
-			return err
-		}
 
-		wg.Go(func() error {
-			return generateHTML(path, outDir, inDir)
-		})
+		switch filepath.Ext(path) {
+		case ".html":
+			path, err = filepath.Rel(inDir, path)
+			if err != nil {
+				return err
+			}
+			wg.Go(func() error {
+				return generateHTML(path, outDir, inDir)
+			})
+		case ".md":
+			path, err = filepath.Rel(inDir, path)
+			if err != nil {
+				return err
+			}
+			wg.Go(func() error {
+				return generateMarkdown(path, outDir, inDir)
+			})
+		}
 		return nil
 	}); err != nil {
 		return err
@@ -209,7 +327,6 @@ func generateHTMLs(outDir, inDir string) error {
 
 func generateHTML(path string, outDir, inDir string) error {
 	inPath := filepath.Join(inDir, path)
-	outPath := filepath.Join(outDir, path)
 
 	in, err := os.Open(inPath)
 	if err != nil {
@@ -222,6 +339,34 @@ func generateHTML(path string, outDir, inDir string) error {
 		return err
 	}
 
+	return finalizeHTML(node, path, outDir, pageMeta{})
+}
+
+// pageMeta holds page metadata that can either be derived from the HTML
+// document itself (the h1 title, the hardcoded description) or be supplied
+// up front, as is the case for Markdown source files with front matter.
+type pageMeta struct {
+	// Title is the page title. If empty, it is derived from the document's
+	// h1 element.
+	Title string
+
+	// Description is the page description. If empty, it is derived from
+	// the document's existing meta description, falling back to a default.
+	Description string
+
+	// Date is the page's publication date, in RFC 3339 or YYYY-MM-DD form.
+	// It is only known for Markdown source files that declare a front
+	// matter "date" field.
+	Date string
+}
+
+// finalizeHTML applies the post-processing shared by every content source
+// (head injection, header insertion, whitespace handling, thin-space
+// insertion) and writes the result to outDir. It is the convergence point
+// for both .html and .md source files.
+func finalizeHTML(node *html.Node, path string, outDir string, meta pageMeta) error {
+	outPath := filepath.Join(outDir, strings.TrimSuffix(path, filepath.Ext(path))+".html")
+
 	htmle := getElementByName(node, "html")
 	if _, ok := getAttribute(htmle, "lang"); !ok {
 		lang := "en"
@@ -237,18 +382,16 @@ func generateHTML(path string, outDir, inDir string) error {
 	}
 
 	head := getElementByName(htmle, "head")
-	if getElement(head, func(n *html.Node) bool {
-		if n.Data != "meta" {
-			return false
+	desc := FindOne(head, "head > meta[name=description]")
+	if desc == nil || meta.Description != "" {
+		if desc != nil {
+			desc.Parent.RemoveChild(desc)
 		}
-		for _, a := range n.Attr {
-			if a.Key == "name" && a.Val == "description" {
-				return true
-			}
+		content := meta.Description
+		if content == "" {
+			// TODO: Generate a good description.
+			content = "Hajime Hoshi is a software engineer in Tokyo"
 		}
-		return false
-	}) == nil {
-		// TODO: Generate a good description.
 		head.AppendChild(&html.Node{
 			Type: html.ElementNode,
 			Data: "meta",
@@ -259,7 +402,7 @@ func generateHTML(path string, outDir, inDir string) error {
 				},
 				{
 					Key: "content",
-					Val: "Hajime Hoshi is a software engineer in Tokyo",
+					Val: content,
 				},
 			},
 		})
@@ -324,6 +467,10 @@ func generateHTML(path string, outDir, inDir string) error {
 	if err != nil {
 		return err
 	}
+	styleIntegrity, err := fileIntegrity(filepath.Join(outDir, "style.css"))
+	if err != nil {
+		return err
+	}
 	head.AppendChild(&html.Node{
 		Type: html.ElementNode,
 		Data: "link",
@@ -354,6 +501,14 @@ func generateHTML(path string, outDir, inDir string) error {
 				Key: "href",
 				Val: fmt.Sprintf("/style.css?%s", h),
 			},
+			{
+				Key: "integrity",
+				Val: styleIntegrity,
+			},
+			{
+				Key: "crossorigin",
+				Val: "anonymous",
+			},
 		},
 	})
 	head.AppendChild(&html.Node{
@@ -375,8 +530,13 @@ func generateHTML(path string, outDir, inDir string) error {
 		},
 	})
 	titleStr := "hajimehoshi.com"
-	if path != "index.html" {
-		title := getElementByName(htmle, "h1").FirstChild.Data
+	title := meta.Title
+	if title == "" {
+		if h1 := FindOne(htmle, "h1"); h1 != nil {
+			title = h1.FirstChild.Data
+		}
+	}
+	if title != "" {
 		titleStr = fmt.Sprintf("%s - %s", title, titleStr)
 	}
 	head.AppendChild(&html.Node{
@@ -402,6 +562,34 @@ func generateHTML(path string, outDir, inDir string) error {
 		Data: cssBuf.String(),
 	})
 	head.AppendChild(style)
+	inlineStyleHash := inlineIntegrity(cssBuf.Bytes())
+
+	if lang, ok := feedScope(path); ok {
+		for _, f := range []struct {
+			typ  string
+			href string
+		}{
+			{"application/rss+xml", fmt.Sprintf("/%s/blog/feed.xml", lang)},
+			{"application/atom+xml", fmt.Sprintf("/%s/blog/atom.xml", lang)},
+		} {
+			head.AppendChild(&html.Node{
+				Type: html.ElementNode,
+				Data: "link",
+				Attr: []html.Attribute{
+					{Key: "rel", Val: "alternate"},
+					{Key: "type", Val: f.typ},
+					{Key: "href", Val: f.href},
+				},
+			})
+		}
+		recordFeedItem(feedItem{
+			Lang:    lang,
+			Title:   title,
+			Path:    path,
+			Date:    meta.Date,
+			Summary: firstParagraphAfterH1(htmle),
+		})
+	}
 
 	if err := addHeader(node); err != nil {
 		return err
@@ -411,6 +599,10 @@ func generateHTML(path string, outDir, inDir string) error {
 	if err != nil {
 		return err
 	}
+	scriptIntegrity, err := fileIntegrity(filepath.Join(outDir, "script.js"))
+	if err != nil {
+		return err
+	}
 	head.AppendChild(&html.Node{
 		Type: html.ElementNode,
 		Data: "script",
@@ -422,22 +614,45 @@ func generateHTML(path string, outDir, inDir string) error {
 			{
 				Key: "defer",
 			},
+			{
+				Key: "integrity",
+				Val: scriptIntegrity,
+			},
+			{
+				Key: "crossorigin",
+				Val: "anonymous",
+			},
 		},
 	})
 
+	csp := buildCSP([]string{inlineStyleHash}, nil)
+	head.AppendChild(&html.Node{
+		Type: html.ElementNode,
+		Data: "meta",
+		Attr: []html.Attribute{
+			{Key: "http-equiv", Val: "Content-Security-Policy"},
+			{Key: "content", Val: csp},
+		},
+	})
+	recordCSP(strings.TrimSuffix(path, filepath.Ext(path))+".html", csp)
+
 	removeComments(node)
 	removeInterElementWhitespace(node)
 	processNewLines(node)
-	insertNodeBetweenWideAndNarrow(node, &html.Node{
-		Type: html.ElementNode,
-		Data: "span",
-		Attr: []html.Attribute{
-			{
-				Key: "class",
-				Val: "thin-space",
+	insertNodeBetweenWideAndNarrow(node, ElementSpacer{
+		Template: &html.Node{
+			Type: html.ElementNode,
+			Data: "span",
+			Attr: []html.Attribute{
+				{
+					Key: "class",
+					Val: "thin-space",
+				},
 			},
 		},
 	})
+	transformImages(node)
+	applyTransforms(node)
 
 	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
 		return err
@@ -491,8 +706,8 @@ func getAttribute(node *html.Node, key string) (html.Attribute, bool) {
 }
 
 func addHeader(node *html.Node) error {
-	body := getElementByName(node, "body")
-	main := getElementByName(node, "main")
+	body := FindOne(node, "body")
+	main := FindOne(body, "body > main")
 
 	f, err := htmlFiles.Open("header.html")
 	if err != nil {
@@ -667,7 +882,10 @@ func processNewLines(node *html.Node) {
 	}
 }
 
-func insertNodeBetweenWideAndNarrow(node *html.Node, insertingNode *html.Node) {
+// insertNodeBetweenWideAndNarrow walks node's descendants and asks strategy
+// to separate every wide/narrow rune boundary it finds in text content, e.g.
+// inserting a <span class="thin-space"> between "foo" and "あ".
+func insertNodeBetweenWideAndNarrow(node *html.Node, strategy SpacingStrategy) {
 	if node.Type == html.ElementNode {
 		if isMetadataElementName(node.Data) {
 			return
@@ -677,7 +895,7 @@ func insertNodeBetweenWideAndNarrow(node *html.Node, insertingNode *html.Node) {
 		}
 	}
 
-	// Insert dummy empty text nodes between two elements. This might be replaced with insertingNode later.
+	// Insert dummy empty text nodes between two elements. This might be replaced by the strategy later.
 	var next *html.Node
 	for n := node.FirstChild; n != nil; n = next {
 		next = n.NextSibling
@@ -710,7 +928,7 @@ func insertNodeBetweenWideAndNarrow(node *html.Node, insertingNode *html.Node) {
 			if i == 0 {
 				continue
 			}
-			if prevR, _ := utf8.DecodeLastRuneInString(n.Data[:i]); shouldHaveThinSpace(prevR, r) {
+			if prevR, _ := utf8.DecodeLastRuneInString(n.Data[:i]); strategy.ShouldSpace(prevR, r) {
 				tokens = append(tokens, n.Data[lastI:i])
 				lastI = i
 			}
@@ -719,42 +937,61 @@ func insertNodeBetweenWideAndNarrow(node *html.Node, insertingNode *html.Node) {
 
 		prevR := lastRuneBefore(n)
 		nextR := firstRuneAfter(n)
+		prevNode := prevVisibleTextNode(n)
+		nextNode := nextVisibleTextNode(n)
 
 		parent := n.Parent
 		parent.RemoveChild(n)
 
-		insertSpan := func() {
-			node := &html.Node{
-				Type:      insertingNode.Type,
-				DataAtom:  insertingNode.DataAtom,
-				Data:      insertingNode.Data,
-				Namespace: insertingNode.Namespace,
+		tokenNodes := make([]*html.Node, len(tokens))
+		for i, t := range tokens {
+			tokenNodes[i] = &html.Node{Type: html.TextNode, Data: t}
+		}
+
+		// wideSide returns whichever of before/after is the wide side of a
+		// boundary between runes r0 and r1, for strategies (like
+		// CSSMarginSpacer) that wrap the wide side rather than insert
+		// between the two.
+		wideSide := func(r0, r1 rune, before, after *html.Node) *html.Node {
+			switch {
+			case IsWideRune(r0):
+				return before
+			case IsWideRune(r1):
+				return after
+			default:
+				return nil
 			}
-			node.Attr = make([]html.Attribute, len(insertingNode.Attr))
-			copy(node.Attr, insertingNode.Attr)
-			parent.InsertBefore(node, next)
 		}
 
-		if len(tokens) > 0 {
-			if r, _ := utf8.DecodeRuneInString(tokens[0]); shouldHaveThinSpace(prevR, r) {
-				insertSpan()
+		if len(tokenNodes) > 0 {
+			// Attach every token first, in order, all anchored on next (the
+			// already-attached node after the one being split). Only once a
+			// token is attached is it safe to hand it to Apply as an anchor
+			// or as wideNode: html.Node.InsertBefore panics if asked to
+			// attach a node that looks already-attached, which an
+			// unattached *html.Node passed as a later InsertBefore's anchor
+			// would, since Apply may have mutated its sibling pointers.
+			for _, tn := range tokenNodes {
+				parent.InsertBefore(tn, next)
+			}
+
+			if r, _ := utf8.DecodeRuneInString(tokens[0]); strategy.ShouldSpace(prevR, r) {
+				strategy.Apply(parent, tokenNodes[0], wideSide(prevR, r, prevNode, tokenNodes[0]))
 			}
-			for i, t := range tokens {
-				parent.InsertBefore(&html.Node{
-					Type: html.TextNode,
-					Data: t,
-				}, next)
-				if i == len(tokens)-1 {
-					continue
+			for i := 0; i < len(tokenNodes)-1; i++ {
+				r0, _ := utf8.DecodeLastRuneInString(tokens[i])
+				r1, _ := utf8.DecodeRuneInString(tokens[i+1])
+				if strategy.ShouldSpace(r0, r1) {
+					strategy.Apply(parent, tokenNodes[i+1], wideSide(r0, r1, tokenNodes[i], tokenNodes[i+1]))
 				}
-				insertSpan()
 			}
-			if r, _ := utf8.DecodeLastRuneInString(tokens[len(tokens)-1]); shouldHaveThinSpace(r, nextR) {
-				insertSpan()
+			last := tokenNodes[len(tokenNodes)-1]
+			if r, _ := utf8.DecodeLastRuneInString(tokens[len(tokens)-1]); strategy.ShouldSpace(r, nextR) {
+				strategy.Apply(parent, next, wideSide(r, nextR, last, nextNode))
 			}
 		} else {
-			if shouldHaveThinSpace(prevR, nextR) {
-				insertSpan()
+			if strategy.ShouldSpace(prevR, nextR) {
+				strategy.Apply(parent, next, wideSide(prevR, nextR, prevNode, nextNode))
 			}
 		}
 	}
@@ -764,7 +1001,7 @@ func insertNodeBetweenWideAndNarrow(node *html.Node, insertingNode *html.Node) {
 		if n.Type == html.TextNode {
 			continue
 		}
-		insertNodeBetweenWideAndNarrow(n, insertingNode)
+		insertNodeBetweenWideAndNarrow(n, strategy)
 	}
 
 	// Remove dummy empty text nodes.
@@ -910,10 +1147,8 @@ func shouldHaveThinSpace(r0, r1 rune) bool {
 		return false
 	}
 
-	k0 := width.LookupRune(r0).Kind()
-	k1 := width.LookupRune(r1).Kind()
-	w0 := k0 == width.EastAsianWide || k0 == width.EastAsianFullwidth
-	w1 := k1 == width.EastAsianWide || k1 == width.EastAsianFullwidth
+	w0 := isWideRune(r0)
+	w1 := isWideRune(r1)
 	if w0 == w1 {
 		return false
 	}
@@ -921,6 +1156,13 @@ func shouldHaveThinSpace(r0, r1 rune) bool {
 	return (w0 && !unicode.IsPunct(r0)) != (w1 && !unicode.IsPunct(r1))
 }
 
+// isWideRune reports whether r is East Asian wide or fullwidth, as opposed
+// to narrow (e.g. most Latin letters) or ambiguous/neutral.
+func isWideRune(r rune) bool {
+	k := width.LookupRune(r).Kind()
+	return k == width.EastAsianWide || k == width.EastAsianFullwidth
+}
+
 func isMetadataElementName(name string) bool {
 	for _, n := range []string{"base", "link", "meta", "noscript", "script", "style", "template", "title"} {
 		if name == n {
@@ -1007,3 +1249,42 @@ func minifyJS(out io.Writer, in io.Reader) error {
 	}
 	return nil
 }
+
+// writeBuildCache records a fresh buildCache for every content file under
+// inDir and persists it to outDir, so a later RunIncremental call has a
+// baseline to diff against.
+func writeBuildCache(outDir, inDir string) error {
+	cache := &buildCache{Entries: map[string]buildCacheEntry{}}
+	if err := filepath.Walk(inDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || isIgnoredFile(path) {
+			return nil
+		}
+		switch filepath.Ext(path) {
+		case ".html", ".md":
+		default:
+			return nil
+		}
+		rel, err := filepath.Rel(inDir, path)
+		if err != nil {
+			return err
+		}
+		return recordBuildCacheEntry(cache, rel, outDir)
+	}); err != nil {
+		return err
+	}
+	return cache.save(outDir)
+}
+
+// recordBuildCacheEntry records the dependency hash for the output produced
+// from the content file at rel (relative to contents/) into cache.
+func recordBuildCacheEntry(cache *buildCache, rel, outDir string) error {
+	inputs := []string{
+		filepath.Join("contents", rel),
+		filepath.Join(outDir, "style.css"),
+		filepath.Join(outDir, "script.js"),
+	}
+	return cache.record(rel, inputs)
+}
@@ -315,6 +315,108 @@ func TestProcessNewLines(t *testing.T) {
 	}
 }
 
+func TestRenderPlainText(t *testing.T) {
+	testCases := []struct {
+		In  string
+		Out string
+	}{
+		{
+			In:  "<p>foo</p>",
+			Out: "foo",
+		},
+		{
+			In:  "<p>foo</p><p>bar</p>",
+			Out: "foo\n\nbar",
+		},
+		{
+			In:  `<p>foo<a href="https://example.com">bar</a></p>`,
+			Out: "foobar (https://example.com)",
+		},
+		{
+			In:  "<ul><li>foo</li><li>bar</li></ul>",
+			Out: "- foo\n- bar",
+		},
+		{
+			In:  "<ol><li>foo</li><li>bar</li></ol>",
+			Out: "1. foo\n2. bar",
+		},
+		{
+			In:  "<p>fooあ</p>",
+			Out: "foo\u2009あ", // THIN SPACE, not an ASCII space
+		},
+	}
+	for _, tc := range testCases {
+		nodes, err := html.ParseFragment(bytes.NewBufferString(tc.In), nil)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		got, err := gen.RenderPlainText(nodes[0], gen.Options{})
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if got != tc.Out {
+			t.Errorf("got: %q, want: %q (in: %q)", got, tc.Out, tc.In)
+		}
+	}
+}
+
+func TestExtractHeadingsAndBuildTOC(t *testing.T) {
+	nodes, err := html.ParseFragment(bytes.NewBufferString(`
+		<h2>Intro</h2>
+		<h3>Details</h3>
+		<h2>Intro</h2>
+		<h2>!!!</h2>
+	`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := nodes[0]
+
+	headings := gen.ExtractHeadings(node)
+	if got, want := len(headings), 3; got != want {
+		t.Fatalf("len(ExtractHeadings(node)): got: %d, want: %d", got, want)
+	}
+	if got, want := headings[0].ID, "intro"; got != want {
+		t.Errorf("headings[0].ID: got: %q, want: %q", got, want)
+	}
+	if got, want := headings[2].ID, "intro-2"; got != want {
+		t.Errorf("headings[2].ID: got: %q, want: %q", got, want)
+	}
+
+	toc := gen.BuildTOC(node, gen.TOCOptions{})
+	var out bytes.Buffer
+	if err := html.Render(&out, toc); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), `<nav><ol><li><a href="#intro">Intro</a><ol><li><a href="#details">Details</a></li></ol></li><li><a href="#intro-2">Intro</a></li></ol></nav>`; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
+
+func TestFindAndFindOne(t *testing.T) {
+	nodes, err := html.ParseFragment(bytes.NewBufferString(`<div><p class="a">foo</p><p class="b">bar</p></div>`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := nodes[0]
+
+	if got, want := len(gen.Find(node, "p")), 2; got != want {
+		t.Errorf("len(Find(node, %q)): got: %d, want: %d", "p", got, want)
+	}
+
+	one := gen.FindOne(node, "p.b")
+	if one == nil || one.FirstChild.Data != "bar" {
+		t.Errorf("FindOne(node, %q): got: %v, want a <p> with text %q", "p.b", one, "bar")
+	}
+
+	if got := gen.FindOne(node, "p.c"); got != nil {
+		t.Errorf("FindOne(node, %q): got: %v, want: nil", "p.c", got)
+	}
+}
+
 func TestInsertNodeBetweenWideAndNarrow(t *testing.T) {
 	testCases := []struct {
 		In  string
@@ -352,6 +454,18 @@ func TestInsertNodeBetweenWideAndNarrow(t *testing.T) {
 			In:  "<ul><li>foo</li><li>あ</li></ul>",
 			Out: "<ul><li>foo</li><li>あ</li></ul>",
 		},
+		{
+			In:  "<p>foo「あ」</p>",
+			Out: "<p>foo「あ」</p>",
+		},
+		{
+			In:  "<p>「あ」foo</p>",
+			Out: "<p>「あ」foo</p>",
+		},
+		{
+			In:  "<p>foo・あ</p>",
+			Out: "<p>foo・あ</p>",
+		},
 	}
 	for _, tc := range testCases {
 		nodes, err := html.ParseFragment(bytes.NewBufferString(tc.In), nil)
@@ -361,9 +475,11 @@ func TestInsertNodeBetweenWideAndNarrow(t *testing.T) {
 		}
 
 		node := nodes[0]
-		gen.InsertNodeBetweenWideAndNarrow(node, &html.Node{
-			Type: html.ElementNode,
-			Data: "dummy-space",
+		gen.InsertNodeBetweenWideAndNarrow(node, gen.ElementSpacer{
+			Template: &html.Node{
+				Type: html.ElementNode,
+				Data: "dummy-space",
+			},
 		})
 
 		var out bytes.Buffer
@@ -380,3 +496,52 @@ func TestInsertNodeBetweenWideAndNarrow(t *testing.T) {
 		}
 	}
 }
+
+func TestInsertNodeBetweenWideAndNarrowUnicodeSpacer(t *testing.T) {
+	nodes, err := html.ParseFragment(bytes.NewBufferString("<p>fooあ</p>"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node := nodes[0]
+	gen.InsertNodeBetweenWideAndNarrow(node, gen.UnicodeSpacer{Rune: ' '})
+
+	var out bytes.Buffer
+	if err := html.Render(&out, node); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	got = strings.TrimPrefix(got, "<html><head></head><body>")
+	got = strings.TrimSuffix(got, "</body></html>")
+	want := "<p>foo あ</p>"
+	if got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
+
+func TestInsertNodeBetweenWideAndNarrowCSSMarginSpacer(t *testing.T) {
+	// A regression test for a panic: a single text node spanning more than
+	// one wide/narrow boundary ("あ", "foo", "い" here) used to crash with
+	// "html: InsertBefore called for an attached child Node", because the
+	// token on the far side of an internal boundary hadn't been attached to
+	// the tree yet when Apply was called for it.
+	nodes, err := html.ParseFragment(bytes.NewBufferString("<p>あfooい</p>"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node := nodes[0]
+	gen.InsertNodeBetweenWideAndNarrow(node, gen.CSSMarginSpacer{})
+
+	var out bytes.Buffer
+	if err := html.Render(&out, node); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	got = strings.TrimPrefix(got, "<html><head></head><body>")
+	got = strings.TrimSuffix(got, "</body></html>")
+	want := `<p><span style="margin-inline: 0.25em">あ</span>foo<span style="margin-inline: 0.25em">い</span></p>`
+	if got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
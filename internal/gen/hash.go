@@ -7,6 +7,7 @@ import (
 	"bufio"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/json"
 	"io"
 	"os"
 	"sync"
@@ -25,6 +26,21 @@ func fileHash(path string) (string, error) {
 		return h, nil
 	}
 
+	hash, err := hashFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	if fileHashCache == nil {
+		fileHashCache = map[string]string{}
+	}
+	fileHashCache[path] = hash
+
+	return hash, nil
+}
+
+// hashFile hashes the file at path, reading it fresh from disk every call.
+func hashFile(path string) (string, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return "", err
@@ -35,12 +51,128 @@ func fileHash(path string) (string, error) {
 	if _, err := io.Copy(h, bufio.NewReader(f)); err != nil {
 		return "", err
 	}
-	hash := base64.URLEncoding.EncodeToString(h.Sum(nil))[:10]
+	return base64.URLEncoding.EncodeToString(h.Sum(nil))[:10], nil
+}
 
-	if fileHashCache == nil {
-		fileHashCache = map[string]string{}
+// forgetFileHash drops path from the in-memory hash cache, so a subsequent
+// fileHash call re-reads it from disk. It is used by incremental rebuilds
+// after a watched file has changed on disk.
+func forgetFileHash(path string) {
+	fileHashCacheM.Lock()
+	defer fileHashCacheM.Unlock()
+	delete(fileHashCache, path)
+}
+
+// buildCacheEntry records the dependency hashes that produced an output
+// file, so a later run can tell whether it is still up to date.
+type buildCacheEntry struct {
+	// Inputs are the input paths (relative to the working directory) that
+	// were read to produce the output, e.g. the source content file plus
+	// the shared style.css and script.js under outDir.
+	Inputs []string `json:"inputs"`
+
+	// Hash is the combined hash of all Inputs at the time the output was
+	// generated.
+	Hash string `json:"hash"`
+}
+
+// buildCache is the on-disk record of which inputs produced which outputs,
+// persisted to outDir/.gen-cache.json so incremental rebuilds can skip
+// outputs whose dependencies have not changed.
+type buildCache struct {
+	// Entries maps a source content file's path (relative to contents/,
+	// e.g. "en/blog/foo.md") to the entry that produced its output.
+	Entries map[string]buildCacheEntry `json:"entries"`
+}
+
+const buildCacheFileName = ".gen-cache.json"
+
+func loadBuildCache(outDir string) (*buildCache, error) {
+	f, err := os.Open(buildCachePath(outDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &buildCache{Entries: map[string]buildCacheEntry{}}, nil
+		}
+		return nil, err
 	}
-	fileHashCache[path] = hash
+	defer f.Close()
 
-	return hash, nil
+	var c buildCache
+	if err := json.NewDecoder(f).Decode(&c); err != nil {
+		return nil, err
+	}
+	if c.Entries == nil {
+		c.Entries = map[string]buildCacheEntry{}
+	}
+	return &c, nil
+}
+
+func (c *buildCache) save(outDir string) error {
+	f, err := os.Create(buildCachePath(outDir))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
+
+func buildCachePath(outDir string) string {
+	return outDir + string(os.PathSeparator) + buildCacheFileName
+}
+
+// depsHash computes a combined hash over the given input file paths, for
+// storage in a buildCacheEntry. It hashes each path via hashFile rather than
+// the memoized fileHash: buildCache must detect a path's bytes changing
+// between two separate upToDate calls in a long-lived process (the dev
+// server), which fileHash's cache, built to avoid re-hashing within a
+// single build pass, would otherwise paper over.
+func depsHash(paths []string) (string, error) {
+	h := sha256.New()
+	for _, p := range paths {
+		hh, err := hashFile(p)
+		if err != nil {
+			return "", err
+		}
+		if _, err := io.WriteString(h, hh); err != nil {
+			return "", err
+		}
+	}
+	return base64.URLEncoding.EncodeToString(h.Sum(nil))[:10], nil
+}
+
+// upToDate reports whether the output at outPath is already recorded in the
+// cache with the given dependency set unchanged.
+func (c *buildCache) upToDate(outPath string, inputs []string) (bool, error) {
+	entry, ok := c.Entries[outPath]
+	if !ok {
+		return false, nil
+	}
+	if len(entry.Inputs) != len(inputs) {
+		return false, nil
+	}
+	for i := range inputs {
+		if entry.Inputs[i] != inputs[i] {
+			return false, nil
+		}
+	}
+	h, err := depsHash(inputs)
+	if err != nil {
+		return false, err
+	}
+	return h == entry.Hash, nil
+}
+
+func (c *buildCache) record(outPath string, inputs []string) error {
+	h, err := depsHash(inputs)
+	if err != nil {
+		return err
+	}
+	c.Entries[outPath] = buildCacheEntry{
+		Inputs: inputs,
+		Hash:   h,
+	}
+	return nil
 }
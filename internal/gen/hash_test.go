@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 Hajime Hoshi
+
+package gen_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hajimehoshi/hajimehoshi.com/internal/gen"
+)
+
+func TestBuildCacheUpToDate(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "post.md")
+	if err := os.WriteFile(input, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cache, err := gen.LoadBuildCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inputs := []string{input}
+	if upToDate, err := cache.UpToDate("post.html", inputs); err != nil {
+		t.Fatal(err)
+	} else if upToDate {
+		t.Fatal("a never-recorded output reported up to date")
+	}
+
+	if err := cache.Record("post.html", inputs); err != nil {
+		t.Fatal(err)
+	}
+	if upToDate, err := cache.UpToDate("post.html", inputs); err != nil {
+		t.Fatal(err)
+	} else if !upToDate {
+		t.Fatal("a freshly recorded output with unchanged inputs reported stale")
+	}
+
+	if err := os.WriteFile(input, []byte("hello, world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if upToDate, err := cache.UpToDate("post.html", inputs); err != nil {
+		t.Fatal(err)
+	} else if upToDate {
+		t.Fatal("an output reported up to date after an input's contents changed")
+	}
+}
+
+func TestBuildCacheUpToDateInputSetChanged(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.md")
+	b := filepath.Join(dir, "b.css")
+	for _, p := range []string{a, b} {
+		if err := os.WriteFile(p, []byte(p), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cache, err := gen.LoadBuildCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Record("post.html", []string{a}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Same content hash for "a" alone, but the dependency set grew to
+	// include "b" too (e.g. a shared partial was newly referenced).
+	if upToDate, err := cache.UpToDate("post.html", []string{a, b}); err != nil {
+		t.Fatal(err)
+	} else if upToDate {
+		t.Fatal("an output reported up to date after its input set changed")
+	}
+}
+
+func TestBuildCacheSaveAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "post.md")
+	if err := os.WriteFile(input, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cache, err := gen.LoadBuildCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Record("post.html", []string{input}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := gen.LoadBuildCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upToDate, err := reloaded.UpToDate("post.html", []string{input}); err != nil {
+		t.Fatal(err)
+	} else if !upToDate {
+		t.Fatal("a reloaded cache did not consider its recorded entry up to date")
+	}
+}
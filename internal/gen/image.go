@@ -0,0 +1,195 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 Hajime Hoshi
+
+package gen
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/chai2010/webp"
+	"golang.org/x/image/draw"
+	"golang.org/x/net/html"
+)
+
+// imageVariantWidths are the srcset widths generated for every responsive
+// image, in addition to the original.
+var imageVariantWidths = []int{480, 960, 1920}
+
+// imageVariant is one resized rendition of a source image.
+type imageVariant struct {
+	Width int
+	Path  string // relative to outDir, with a content-hash suffix
+}
+
+// imageManifestEntry is everything generateHTML needs to rewrite an <img>
+// referencing the source image into a responsive <picture>.
+type imageManifestEntry struct {
+	Width    int
+	Height   int
+	Variants []imageVariant // ascending by Width, largest last is the original size
+	WebPPath string         // relative to outDir; "" if no WebP alternate could be produced
+}
+
+var (
+	imageManifestM sync.Mutex
+	imageManifest  = map[string]imageManifestEntry{} // keyed by path relative to outDir, e.g. "photos/a.png"
+)
+
+func isImageFile(path string) bool {
+	switch filepath.Ext(path) {
+	case ".png", ".jpg", ".jpeg":
+		return true
+	}
+	return false
+}
+
+// generateImageVariants decodes the image at path (already copied verbatim
+// to outPath by copyNonHTMLFiles), produces width variants and a WebP
+// alternate, and records them in imageManifest keyed by outRelPath.
+func generateImageVariants(outDir, outRelPath, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	src, format, err := image.Decode(f)
+	if err != nil {
+		return fmt.Errorf("gen: decoding image %s: %w", path, err)
+	}
+
+	bounds := src.Bounds()
+	entry := imageManifestEntry{
+		Width:  bounds.Dx(),
+		Height: bounds.Dy(),
+	}
+
+	for _, w := range imageVariantWidths {
+		if w >= bounds.Dx() {
+			continue
+		}
+		h := bounds.Dy() * w / bounds.Dx()
+		dst := image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+
+		var buf bytes.Buffer
+		if err := encodeImage(&buf, dst, format); err != nil {
+			return err
+		}
+		variantPath, err := writeHashedFile(outDir, outRelPath, w, filepath.Ext(outRelPath), buf.Bytes())
+		if err != nil {
+			return err
+		}
+		entry.Variants = append(entry.Variants, imageVariant{Width: w, Path: variantPath})
+	}
+	entry.Variants = append(entry.Variants, imageVariant{Width: bounds.Dx(), Path: outRelPath})
+
+	// No pure-Go AVIF encoder exists yet; fall back to WebP only, and
+	// leave WebPPath empty (the srcset variants still apply) if even that
+	// fails.
+	var webpBuf bytes.Buffer
+	if err := webp.Encode(&webpBuf, src, &webp.Options{Quality: 80}); err == nil {
+		webpPath, err := writeHashedFile(outDir, outRelPath, bounds.Dx(), ".webp", webpBuf.Bytes())
+		if err != nil {
+			return err
+		}
+		entry.WebPPath = webpPath
+	}
+
+	imageManifestM.Lock()
+	imageManifest[outRelPath] = entry
+	imageManifestM.Unlock()
+	return nil
+}
+
+func encodeImage(w *bytes.Buffer, img image.Image, format string) error {
+	switch format {
+	case "png", "gif":
+		return png.Encode(w, img)
+	default:
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+	}
+}
+
+// writeHashedFile writes data under outDir alongside outRelPath, suffixing
+// the file name with the image width and a content hash so variants never
+// collide, and returns the path relative to outDir.
+func writeHashedFile(outDir, outRelPath string, width int, ext string, data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	hash := base64.RawURLEncoding.EncodeToString(sum[:])[:10]
+
+	base := outRelPath[:len(outRelPath)-len(filepath.Ext(outRelPath))]
+	rel := fmt.Sprintf("%s-%dw-%s%s", base, width, hash, ext)
+
+	out := filepath.Join(outDir, rel)
+	if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(out, data, 0644); err != nil {
+		return "", err
+	}
+	return rel, nil
+}
+
+// transformImages rewrites every <img src="..."> in node into a responsive
+// <picture> with srcset, sizes, and intrinsic width/height, using the
+// variants recorded in imageManifest. An <img> can opt out by carrying a
+// data-no-transform attribute.
+func transformImages(node *html.Node) {
+	for _, img := range Find(node, "img") {
+		if _, ok := getAttribute(img, "data-no-transform"); ok {
+			continue
+		}
+		src, ok := getAttribute(img, "src")
+		if !ok {
+			continue
+		}
+
+		imageManifestM.Lock()
+		entry, ok := imageManifest[strings.TrimPrefix(src.Val, "/")]
+		imageManifestM.Unlock()
+		if !ok {
+			continue
+		}
+
+		picture := &html.Node{Type: html.ElementNode, Data: "picture"}
+		if entry.WebPPath != "" {
+			picture.AppendChild(&html.Node{
+				Type: html.ElementNode,
+				Data: "source",
+				Attr: []html.Attribute{
+					{Key: "type", Val: "image/webp"},
+					{Key: "srcset", Val: "/" + entry.WebPPath},
+				},
+			})
+		}
+
+		var srcset []string
+		for _, v := range entry.Variants {
+			srcset = append(srcset, fmt.Sprintf("/%s %dw", v.Path, v.Width))
+		}
+		img.Attr = append(img.Attr,
+			html.Attribute{Key: "srcset", Val: strings.Join(srcset, ", ")},
+			html.Attribute{Key: "sizes", Val: "100vw"},
+			html.Attribute{Key: "width", Val: strconv.Itoa(entry.Width)},
+			html.Attribute{Key: "height", Val: strconv.Itoa(entry.Height)},
+		)
+
+		parent := img.Parent
+		parent.InsertBefore(picture, img)
+		parent.RemoveChild(img)
+		picture.AppendChild(img)
+	}
+}
@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 Hajime Hoshi
+
+package gen_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+
+	"github.com/hajimehoshi/hajimehoshi.com/internal/gen"
+)
+
+func TestTransformImages(t *testing.T) {
+	gen.SetImageManifestEntry("photos/a.png", gen.ImageManifestEntry{
+		Width:  1920,
+		Height: 1080,
+		Variants: []gen.ImageVariant{
+			{Width: 480, Path: "photos/a-480w-abc1234567.png"},
+			{Width: 960, Path: "photos/a-960w-abc1234567.png"},
+			{Width: 1920, Path: "photos/a.png"},
+		},
+		WebPPath: "photos/a-1920w-abc1234567.webp",
+	})
+
+	nodes, err := html.ParseFragment(bytes.NewBufferString(`<p><img src="/photos/a.png"></p>`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := nodes[0]
+	gen.TransformImages(node)
+
+	var out bytes.Buffer
+	if err := html.Render(&out, node); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+
+	for _, want := range []string{
+		"<picture>",
+		`<source type="image/webp" srcset="/photos/a-1920w-abc1234567.webp"/>`,
+		`srcset="/photos/a-480w-abc1234567.png 480w, /photos/a-960w-abc1234567.png 960w, /photos/a.png 1920w"`,
+		`width="1920"`,
+		`height="1080"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestTransformImagesOptOut(t *testing.T) {
+	gen.SetImageManifestEntry("photos/b.png", gen.ImageManifestEntry{Width: 100, Height: 100})
+
+	nodes, err := html.ParseFragment(bytes.NewBufferString(`<p><img src="/photos/b.png" data-no-transform></p>`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := nodes[0]
+	gen.TransformImages(node)
+
+	var out bytes.Buffer
+	if err := html.Render(&out, node); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); strings.Contains(got, "<picture>") {
+		t.Errorf("an <img data-no-transform> was rewritten into a <picture>:\n%s", got)
+	}
+}
+
+func TestTransformImagesUnknownSrc(t *testing.T) {
+	nodes, err := html.ParseFragment(bytes.NewBufferString(`<p><img src="/photos/unknown.png"></p>`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := nodes[0]
+	gen.TransformImages(node)
+
+	var out bytes.Buffer
+	if err := html.Render(&out, node); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); strings.Contains(got, "<picture>") {
+		t.Errorf("an <img> with no manifest entry was rewritten into a <picture>:\n%s", got)
+	}
+}
@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 Hajime Hoshi
+
+package gen
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/yuin/goldmark"
+	"golang.org/x/net/html"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed layouts/*.html
+var layoutFiles embed.FS
+
+const defaultLayout = "default"
+
+// frontMatter is the set of fields a Markdown source file can declare in its
+// front matter block.
+type frontMatter struct {
+	Title       string `yaml:"title" toml:"title"`
+	Description string `yaml:"description" toml:"description"`
+	Date        string `yaml:"date" toml:"date"`
+	Lang        string `yaml:"lang" toml:"lang"`
+	Layout      string `yaml:"layout" toml:"layout"`
+}
+
+// splitFrontMatter splits src into its front matter block and body. It
+// supports YAML front matter delimited by "---" and TOML front matter
+// delimited by "+++". If src has no front matter, fm is the zero value and
+// body is src unchanged.
+func splitFrontMatter(src []byte) (fm frontMatter, body []byte, err error) {
+	for _, delim := range []string{"---", "+++"} {
+		prefix := []byte(delim + "\n")
+		if !bytes.HasPrefix(src, prefix) {
+			continue
+		}
+		rest := src[len(prefix):]
+		end := bytes.Index(rest, []byte("\n"+delim))
+		if end == -1 {
+			return frontMatter{}, nil, fmt.Errorf("gen: unterminated front matter (missing closing %q)", delim)
+		}
+		raw := rest[:end]
+		body := rest[end+len(delim)+1:]
+		body = bytes.TrimPrefix(body, []byte("\n"))
+
+		switch delim {
+		case "---":
+			if err := yaml.Unmarshal(raw, &fm); err != nil {
+				return frontMatter{}, nil, fmt.Errorf("gen: parsing YAML front matter: %w", err)
+			}
+		case "+++":
+			if err := toml.Unmarshal(raw, &fm); err != nil {
+				return frontMatter{}, nil, fmt.Errorf("gen: parsing TOML front matter: %w", err)
+			}
+		}
+		return fm, body, nil
+	}
+	return frontMatter{}, src, nil
+}
+
+func generateMarkdown(path string, outDir, inDir string) error {
+	inPath := filepath.Join(inDir, path)
+
+	src, err := os.ReadFile(inPath)
+	if err != nil {
+		return err
+	}
+
+	fm, body, err := splitFrontMatter(src)
+	if err != nil {
+		return fmt.Errorf("gen: %s: %w", inPath, err)
+	}
+
+	var htmlBuf bytes.Buffer
+	if err := goldmark.Convert(body, &htmlBuf); err != nil {
+		return fmt.Errorf("gen: %s: rendering Markdown: %w", inPath, err)
+	}
+
+	layoutName := fm.Layout
+	if layoutName == "" {
+		layoutName = defaultLayout
+	}
+	layout, err := layoutFiles.Open(filepath.Join("layouts", layoutName+".html"))
+	if err != nil {
+		return fmt.Errorf("gen: %s: loading layout %q: %w", inPath, layoutName, err)
+	}
+	defer layout.Close()
+
+	node, err := html.Parse(layout)
+	if err != nil {
+		return err
+	}
+
+	content := FindOne(node, "#content")
+	if content == nil {
+		return fmt.Errorf("gen: %s: layout %q has no element with id=\"content\"", inPath, layoutName)
+	}
+
+	articleNodes, err := html.ParseFragment(&htmlBuf, content)
+	if err != nil {
+		return err
+	}
+	for _, n := range articleNodes {
+		content.AppendChild(n)
+	}
+
+	if fm.Date != "" {
+		content.Attr = append(content.Attr, html.Attribute{
+			Key: "data-date",
+			Val: fm.Date,
+		})
+	}
+
+	if fm.Lang != "" {
+		htmle := getElementByName(node, "html")
+		htmle.Attr = append(htmle.Attr, html.Attribute{
+			Key: "lang",
+			Val: fm.Lang,
+		})
+	}
+
+	path = strings.TrimSuffix(path, filepath.Ext(path)) + ".html"
+	return finalizeHTML(node, path, outDir, pageMeta{
+		Title:       fm.Title,
+		Description: fm.Description,
+		Date:        fm.Date,
+	})
+}
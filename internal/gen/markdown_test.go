@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 Hajime Hoshi
+
+package gen_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hajimehoshi/hajimehoshi.com/internal/gen"
+)
+
+func TestSplitFrontMatterYAML(t *testing.T) {
+	src := "---\ntitle: Hello\nlang: en\n---\n# Hi\n"
+
+	fm, body, err := gen.SplitFrontMatter([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fm.Title != "Hello" {
+		t.Errorf("Title = %q, want %q", fm.Title, "Hello")
+	}
+	if fm.Lang != "en" {
+		t.Errorf("Lang = %q, want %q", fm.Lang, "en")
+	}
+	if got, want := string(body), "# Hi\n"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestSplitFrontMatterTOML(t *testing.T) {
+	src := "+++\ntitle = \"Hello\"\nlang = \"en\"\n+++\n# Hi\n"
+
+	fm, body, err := gen.SplitFrontMatter([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fm.Title != "Hello" {
+		t.Errorf("Title = %q, want %q", fm.Title, "Hello")
+	}
+	if fm.Lang != "en" {
+		t.Errorf("Lang = %q, want %q", fm.Lang, "en")
+	}
+	if got, want := string(body), "# Hi\n"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestSplitFrontMatterNone(t *testing.T) {
+	src := "# Hi\n"
+
+	fm, body, err := gen.SplitFrontMatter([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fm.Title != "" {
+		t.Errorf("Title = %q, want %q", fm.Title, "")
+	}
+	if got, want := string(body), src; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestSplitFrontMatterUnterminated(t *testing.T) {
+	src := "---\ntitle: Hello\n# Hi\n"
+
+	_, _, err := gen.SplitFrontMatter([]byte(src))
+	if err == nil {
+		t.Fatal("expected an error for unterminated front matter, got nil")
+	}
+	if !strings.Contains(err.Error(), "unterminated front matter") {
+		t.Errorf("error = %q, want it to mention unterminated front matter", err)
+	}
+}
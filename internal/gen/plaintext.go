@@ -0,0 +1,147 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 Hajime Hoshi
+
+package gen
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Options configures RenderPlainText.
+type Options struct {
+	// Separator is inserted between a wide (CJK) run and a narrow (Latin)
+	// run, in place of the ASCII space an html-to-text converter would
+	// normally use. It defaults to U+2009 THIN SPACE.
+	Separator string
+
+	// LinkFormat formats an <a> element as fmt.Sprintf(LinkFormat, text,
+	// href). It defaults to "%s (%s)".
+	LinkFormat string
+}
+
+func (o Options) withDefaults() Options {
+	if o.Separator == "" {
+		o.Separator = "\u2009" // THIN SPACE, spelled out explicitly so it isn't mistaken for an ASCII space
+	}
+	if o.LinkFormat == "" {
+		o.LinkFormat = "%s (%s)"
+	}
+	return o
+}
+
+// isBlockElementName reports whether name is a block-level element whose
+// content RenderPlainText separates from what follows with a paragraph
+// break, mirroring isPhrasingElementName's inline/block split.
+func isBlockElementName(name string) bool {
+	return !isPhrasingElementName(name)
+}
+
+// RenderPlainText walks node's children and renders a plain-text
+// representation suitable for RSS/Atom <description> bodies and OpenGraph
+// descriptions. Block-level elements become paragraph breaks, <a> becomes
+// "text (href)", and <ul>/<ol> items are prefixed with "- " / "1. ". script
+// and style elements are dropped. Like ProcessNewLines and
+// InsertNodeBetweenWideAndNarrow, it classifies runs as wide (CJK) or
+// narrow (Latin) and inserts Options.Separator at the boundary instead of
+// an ASCII space.
+func RenderPlainText(node *html.Node, opts Options) (string, error) {
+	opts = opts.withDefaults()
+	return renderPlainTextChildren(node, opts), nil
+}
+
+// renderPlainTextChildren renders node's children (not node itself, so that
+// the "a" case below can call it on the <a> node without walking straight
+// back into that same case) as plain text, using opts with defaults already
+// applied.
+func renderPlainTextChildren(node *html.Node, opts Options) string {
+	var sb strings.Builder
+	lastRune := rune(-1)
+
+	emit := func(s string) {
+		for _, r := range s {
+			if lastRune != -1 && shouldHaveThinSpace(lastRune, r) {
+				sb.WriteString(opts.Separator)
+			}
+			sb.WriteRune(r)
+			lastRune = r
+		}
+	}
+
+	breakParagraph := func() {
+		s := sb.String()
+		switch {
+		case strings.HasSuffix(s, "\n\n"):
+		case strings.HasSuffix(s, "\n"):
+			sb.WriteString("\n")
+		case s != "":
+			sb.WriteString("\n\n")
+		}
+		lastRune = -1
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			emit(n.Data)
+		case html.ElementNode:
+			switch n.Data {
+			case "script", "style":
+				return
+			case "br":
+				sb.WriteString("\n")
+				lastRune = -1
+				return
+			case "a":
+				text := renderPlainTextChildren(n, opts)
+				href, ok := getAttribute(n, "href")
+				if ok && href.Val != "" {
+					emit(fmt.Sprintf(opts.LinkFormat, text, href.Val))
+				} else {
+					emit(text)
+				}
+				return
+			case "li":
+				sb.WriteString(listItemPrefix(n))
+				lastRune = -1
+				for c := n.FirstChild; c != nil; c = c.NextSibling {
+					walk(c)
+				}
+				sb.WriteString("\n")
+				lastRune = -1
+				return
+			}
+
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				walk(c)
+			}
+			if isBlockElementName(n.Data) {
+				breakParagraph()
+			}
+		}
+	}
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		walk(c)
+	}
+
+	return strings.TrimSpace(sb.String())
+}
+
+// listItemPrefix returns "- " for an <li> under a <ul>, or "<n>. " for an
+// <li> under an <ol>, where n is the item's 1-based position among its
+// sibling <li> elements.
+func listItemPrefix(li *html.Node) string {
+	if li.Parent == nil || li.Parent.Data != "ol" {
+		return "- "
+	}
+	n := 1
+	for s := li.PrevSibling; s != nil; s = s.PrevSibling {
+		if s.Type == html.ElementNode && s.Data == "li" {
+			n++
+		}
+	}
+	return fmt.Sprintf("%d. ", n)
+}
@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 Hajime Hoshi
+
+package gen
+
+import (
+	"sync"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// Find returns every descendant of node (node itself included) that matches
+// the CSS selector.
+func Find(node *html.Node, selector string) []*html.Node {
+	return cascadia.MustCompile(selector).MatchAll(node)
+}
+
+// FindOne returns the first descendant of node that matches the CSS
+// selector, or nil if there is none. Unlike Find, node itself is not
+// considered.
+func FindOne(node *html.Node, selector string) *html.Node {
+	return cascadia.Query(node, cascadia.MustCompile(selector))
+}
+
+// Transform is a function applied to every node matched by a selector
+// registered with RegisterTransform.
+type Transform func(*html.Node)
+
+type registeredTransform struct {
+	selector cascadia.Selector
+	fn       Transform
+}
+
+var (
+	transformsM sync.Mutex
+	transforms  []registeredTransform
+)
+
+// RegisterTransform declares a transform that is applied to every node
+// matching selector during generateHTML, for every page. Typical uses are
+// adding loading="lazy" to img elements or target="_blank" rel="noopener"
+// to external links. Transforms run in registration order after the
+// whitespace and thin-space passes, so they see the final DOM shape.
+func RegisterTransform(selector string, fn Transform) {
+	transformsM.Lock()
+	defer transformsM.Unlock()
+	transforms = append(transforms, registeredTransform{
+		selector: cascadia.MustCompile(selector),
+		fn:       fn,
+	})
+}
+
+// applyTransforms runs every transform registered with RegisterTransform
+// against node.
+func applyTransforms(node *html.Node) {
+	transformsM.Lock()
+	ts := make([]registeredTransform, len(transforms))
+	copy(ts, transforms)
+	transformsM.Unlock()
+
+	for _, t := range ts {
+		for _, n := range t.selector.MatchAll(node) {
+			t.fn(n)
+		}
+	}
+}
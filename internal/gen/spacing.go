@@ -0,0 +1,137 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 Hajime Hoshi
+
+package gen
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// spacingBrackets are wide by Unicode East Asian Width, but as punctuation
+// they already carry their own visual spacing in Japanese typesetting, so a
+// SpacingStrategy should not insert anything next to them even at a
+// wide/narrow boundary.
+const spacingBrackets = "「」『』（）・"
+
+// IsBracketRune reports whether r is a Japanese-style bracket or the
+// katakana middle dot (・), the exceptions SpacingStrategy implementations
+// are expected to skip.
+func IsBracketRune(r rune) bool {
+	return strings.ContainsRune(spacingBrackets, r)
+}
+
+// IsWideRune reports whether r is East Asian wide or fullwidth, the same
+// classification ProcessNewLines uses to distinguish CJK from Latin runs.
+func IsWideRune(r rune) bool {
+	return isWideRune(r)
+}
+
+// DefaultShouldSpace is the rule every built-in SpacingStrategy uses: space
+// a wide run apart from a narrow one, except next to a bracket rune.
+func DefaultShouldSpace(before, after rune) bool {
+	return shouldHaveThinSpace(before, after) && !IsBracketRune(before) && !IsBracketRune(after)
+}
+
+// SpacingStrategy decides what happens at a wide/narrow boundary between
+// two adjacent runes, for InsertNodeBetweenWideAndNarrow.
+type SpacingStrategy interface {
+	// ShouldSpace reports whether this boundary should be separated at
+	// all, given the rune immediately before and after it.
+	ShouldSpace(before, after rune) bool
+
+	// Apply performs the separation at a boundary where ShouldSpace
+	// returned true. parent.InsertBefore(_, before) is the insertion
+	// point an element- or text-based spacer uses. wideNode is whichever
+	// of the two text nodes adjacent to the boundary is on the wide side,
+	// or nil if the boundary falls at the very start or end of the
+	// transformed node's content.
+	Apply(parent *html.Node, before *html.Node, wideNode *html.Node)
+}
+
+// ElementSpacer inserts a clone of Template at the boundary, e.g.
+// <span class="thin-space"></span>. This is InsertNodeBetweenWideAndNarrow's
+// original behavior, and the strategy finalizeHTML and BuildTOC pass to it.
+type ElementSpacer struct {
+	Template *html.Node
+}
+
+func (s ElementSpacer) ShouldSpace(before, after rune) bool {
+	return DefaultShouldSpace(before, after)
+}
+
+func (s ElementSpacer) Apply(parent, before, _ *html.Node) {
+	parent.InsertBefore(cloneShallowNode(s.Template), before)
+}
+
+// UnicodeSpacer inserts a real space character text node — U+2009 THIN
+// SPACE by default, or U+200A HAIR SPACE, or any other rune — instead of an
+// element boundary.
+type UnicodeSpacer struct {
+	// Rune defaults to U+2009 THIN SPACE.
+	Rune rune
+}
+
+func (s UnicodeSpacer) ShouldSpace(before, after rune) bool {
+	return DefaultShouldSpace(before, after)
+}
+
+func (s UnicodeSpacer) Apply(parent, before, _ *html.Node) {
+	r := s.Rune
+	if r == 0 {
+		r = '\u2009' // THIN SPACE, spelled out explicitly so it isn't mistaken for an ASCII space
+	}
+	parent.InsertBefore(&html.Node{Type: html.TextNode, Data: string(r)}, before)
+}
+
+// CSSMarginSpacer wraps the wide side of the boundary in a <span> carrying
+// an inline-direction margin, instead of inserting a new node between the
+// two sides. This avoids introducing an extra DOM boundary inside a link or
+// emphasis element that spans the wide/narrow transition.
+type CSSMarginSpacer struct {
+	// Margin defaults to "0.25em".
+	Margin string
+
+	// ClassName, if set, is added to the wrapping span alongside the
+	// inline style.
+	ClassName string
+}
+
+func (s CSSMarginSpacer) ShouldSpace(before, after rune) bool {
+	return DefaultShouldSpace(before, after)
+}
+
+func (s CSSMarginSpacer) Apply(_ *html.Node, _ *html.Node, wideNode *html.Node) {
+	if wideNode == nil {
+		return
+	}
+	margin := s.Margin
+	if margin == "" {
+		margin = "0.25em"
+	}
+	span := &html.Node{
+		Type: html.ElementNode,
+		Data: "span",
+		Attr: []html.Attribute{{Key: "style", Val: "margin-inline: " + margin}},
+	}
+	if s.ClassName != "" {
+		span.Attr = append(span.Attr, html.Attribute{Key: "class", Val: s.ClassName})
+	}
+	parent := wideNode.Parent
+	parent.InsertBefore(span, wideNode)
+	parent.RemoveChild(wideNode)
+	span.AppendChild(wideNode)
+}
+
+func cloneShallowNode(n *html.Node) *html.Node {
+	c := &html.Node{
+		Type:      n.Type,
+		DataAtom:  n.DataAtom,
+		Data:      n.Data,
+		Namespace: n.Namespace,
+	}
+	c.Attr = make([]html.Attribute, len(n.Attr))
+	copy(c.Attr, n.Attr)
+	return c
+}
@@ -0,0 +1,261 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 Hajime Hoshi
+
+package gen
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// Heading is one heading block found by ExtractHeadings.
+type Heading struct {
+	// Level is the heading level, 2 through 6.
+	Level int
+
+	// Text is the heading's plain text content.
+	Text string
+
+	// ID is the stable slug assigned to the heading, used as both the
+	// element's id and the TOC entry's anchor target.
+	ID string
+
+	// Node is the underlying h2-h6 element, so callers can set its id
+	// attribute.
+	Node *html.Node
+}
+
+// TOCOptions configures BuildTOC.
+type TOCOptions struct {
+	// MinDepth and MaxDepth bound which heading levels are included, as in
+	// h2 through h6. They default to 2 and 6.
+	MinDepth, MaxDepth int
+
+	// AnchorChar prefixes each TOC entry's href, e.g. "#". It defaults to
+	// "#".
+	AnchorChar string
+}
+
+func (o TOCOptions) withDefaults() TOCOptions {
+	if o.MinDepth == 0 {
+		o.MinDepth = 2
+	}
+	if o.MaxDepth == 0 {
+		o.MaxDepth = 6
+	}
+	if o.AnchorChar == "" {
+		o.AnchorChar = "#"
+	}
+	return o
+}
+
+// ExtractHeadings scans node for h2-h6 elements and returns one Heading per
+// recognized heading, in document order, each with a unique slug ID.
+// Headings whose text is only punctuation or emoji (no letters or digits)
+// are skipped, mirroring go/doc's heuristic for recognizing a heading line
+// adapted for a script where case doesn't apply.
+func ExtractHeadings(node *html.Node) []Heading {
+	var headings []Heading
+	slugCount := map[string]int{}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if level, ok := headingLevel(n.Data); ok {
+				text := strings.TrimSpace(textContentForHeading(n))
+				if isHeadingTextMeaningful(text) {
+					id := uniqueSlug(slugify(text), slugCount)
+					headings = append(headings, Heading{
+						Level: level,
+						Text:  text,
+						ID:    id,
+						Node:  n,
+					})
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(node)
+	return headings
+}
+
+func headingLevel(name string) (int, bool) {
+	switch name {
+	case "h2":
+		return 2, true
+	case "h3":
+		return 3, true
+	case "h4":
+		return 4, true
+	case "h5":
+		return 5, true
+	case "h6":
+		return 6, true
+	}
+	return 0, false
+}
+
+// isHeadingTextMeaningful reports whether text contains at least one letter
+// or digit, excluding headings that are only punctuation or emoji.
+func isHeadingTextMeaningful(text string) bool {
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func textContentForHeading(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+// slugify turns text into a URL-safe slug: lowercased, with runs of
+// whitespace and punctuation collapsed to a single hyphen. Text with no
+// ASCII letters or digits (e.g. a Japanese-only heading) falls back to
+// "section".
+func slugify(text string) string {
+	var sb strings.Builder
+	lastHyphen := true // avoid a leading hyphen
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) && r < unicode.MaxASCII, unicode.IsDigit(r) && r < unicode.MaxASCII:
+			sb.WriteRune(r)
+			lastHyphen = false
+		case !lastHyphen:
+			sb.WriteByte('-')
+			lastHyphen = true
+		}
+	}
+	slug := strings.Trim(sb.String(), "-")
+	if slug == "" {
+		return "section"
+	}
+	return slug
+}
+
+func uniqueSlug(slug string, seen map[string]int) string {
+	seen[slug]++
+	id := slug
+	for n := seen[slug]; n > 1; n++ {
+		id = fmt.Sprintf("%s-%d", slug, n)
+		if seen[id] == 0 {
+			seen[slug] = n
+			break
+		}
+	}
+	if id != slug {
+		seen[id]++
+	}
+	return id
+}
+
+// BuildTOC scans node for headings (via ExtractHeadings, bounded by
+// opts.MinDepth/MaxDepth), assigns each one's Node an id attribute, and
+// returns a <nav><ol>...</ol></nav> tree nesting sub-headings under their
+// parent, ready to splice into the page. The returned tree has already been
+// passed through InsertNodeBetweenWideAndNarrow so wide/narrow spacing
+// inside entry text matches the rest of the page.
+func BuildTOC(node *html.Node, opts TOCOptions) *html.Node {
+	opts = opts.withDefaults()
+
+	var headings []Heading
+	for _, h := range ExtractHeadings(node) {
+		if h.Level < opts.MinDepth || h.Level > opts.MaxDepth {
+			continue
+		}
+		headings = append(headings, h)
+	}
+
+	for i, h := range headings {
+		if a, ok := getAttribute(h.Node, "id"); ok {
+			headings[i].ID = a.Val
+		} else {
+			h.Node.Attr = append(h.Node.Attr, html.Attribute{Key: "id", Val: h.ID})
+		}
+	}
+
+	nav := &html.Node{Type: html.ElementNode, Data: "nav"}
+	if len(headings) == 0 {
+		return nav
+	}
+
+	root := &html.Node{Type: html.ElementNode, Data: "ol"}
+	// stack[i] is the <ol> that headings at level i should be appended to.
+	stack := map[int]*html.Node{headings[0].Level: root}
+	nav.AppendChild(root)
+
+	for _, h := range headings {
+		parent, ok := stack[h.Level]
+		if !ok {
+			// No ancestor <ol> exists yet for this level; nest under the
+			// deepest known shallower level, creating one there.
+			for l := h.Level - 1; l >= opts.MinDepth; l-- {
+				if p, ok := stack[l]; ok {
+					var lastLI *html.Node
+					for c := p.LastChild; c != nil; c = c.PrevSibling {
+						if c.Type == html.ElementNode && c.Data == "li" {
+							lastLI = c
+							break
+						}
+					}
+					if lastLI == nil {
+						lastLI = &html.Node{Type: html.ElementNode, Data: "li"}
+						p.AppendChild(lastLI)
+					}
+					parent = &html.Node{Type: html.ElementNode, Data: "ol"}
+					lastLI.AppendChild(parent)
+					stack[h.Level] = parent
+					break
+				}
+			}
+			if parent == nil {
+				parent = root
+			}
+		}
+
+		li := &html.Node{Type: html.ElementNode, Data: "li"}
+		a := &html.Node{
+			Type: html.ElementNode,
+			Data: "a",
+			Attr: []html.Attribute{{Key: "href", Val: opts.AnchorChar + h.ID}},
+		}
+		a.AppendChild(&html.Node{Type: html.TextNode, Data: h.Text})
+		li.AppendChild(a)
+		parent.AppendChild(li)
+
+		for l := range stack {
+			if l > h.Level {
+				delete(stack, l)
+			}
+		}
+	}
+
+	insertNodeBetweenWideAndNarrow(nav, ElementSpacer{
+		Template: &html.Node{
+			Type: html.ElementNode,
+			Data: "span",
+			Attr: []html.Attribute{{Key: "class", Val: "thin-space"}},
+		},
+	})
+
+	return nav
+}
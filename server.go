@@ -6,6 +6,7 @@
 package main
 
 import (
+	"bufio"
 	"errors"
 	"flag"
 	"io"
@@ -13,6 +14,12 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/hajimehoshi/hajimehoshi.com/internal/gen"
 )
 
 var (
@@ -30,6 +37,72 @@ func init() {
 	rootPath = dir
 }
 
+// headersSidecar mirrors the per-path headers recorded in outDir/_headers
+// by gen.Run, keyed by the path as it appears in the sidecar (leading
+// slash, no host). Every request checks the file's modification time and
+// reloads it when that has changed, so edits to content take effect without
+// restarting the server.
+var (
+	headersSidecarM       sync.Mutex
+	headersSidecar        map[string]http.Header
+	headersSidecarModTime int64
+)
+
+// loadHeadersSidecar parses the Netlify-style "_headers" file written by
+// gen.Run: a path on its own line, followed by indented "Key: Value" lines
+// until the next unindented path or EOF.
+func loadHeadersSidecar() (map[string]http.Header, error) {
+	f, err := os.Open(filepath.Join(rootPath, "_headers"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	headers := map[string]http.Header{}
+	var current string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") {
+			current = strings.TrimSpace(line)
+			continue
+		}
+		if current == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		if headers[current] == nil {
+			headers[current] = http.Header{}
+		}
+		headers[current].Set(strings.TrimSpace(k), strings.TrimSpace(v))
+	}
+	return headers, s.Err()
+}
+
+func headersForPath(urlPath string) http.Header {
+	headersSidecarM.Lock()
+	defer headersSidecarM.Unlock()
+
+	if info, err := os.Stat(filepath.Join(rootPath, "_headers")); err == nil {
+		if mt := info.ModTime().UnixNano(); mt != headersSidecarModTime {
+			if h, err := loadHeadersSidecar(); err == nil {
+				headersSidecar = h
+				headersSidecarModTime = mt
+			}
+		}
+	}
+	return headersSidecar[urlPath]
+}
+
 type handler struct{}
 
 func (handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -57,6 +130,11 @@ func (handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Access-Control-Allow-Origin", "*")
+	for k, vs := range headersForPath(r.URL.Path) {
+		for _, v := range vs {
+			w.Header().Add(k, v)
+		}
+	}
 	http.ServeFile(w, r, path)
 }
 
@@ -73,7 +151,57 @@ func notFound(w http.ResponseWriter, r *http.Request) {
 	io.Copy(w, f)
 }
 
+// watchContents watches the contents/ directory and triggers an incremental
+// rebuild whenever a file changes, so the preview served by handler reflects
+// the latest edit without a manual rebuild step.
+func watchContents() error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	if err := filepath.Walk("contents", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return w.Add(path)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			select {
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) == 0 {
+					continue
+				}
+				if err := gen.RunIncremental([]string{ev.Name}); err != nil {
+					log.Println(err)
+				}
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				log.Println(err)
+			}
+		}
+	}()
+	return nil
+}
+
 func main() {
+	if rootPath == "_site" || rootPath == "." {
+		if err := watchContents(); err != nil {
+			log.Fatal(err)
+		}
+	}
 	http.Handle("/", handler{})
 	log.Fatal(http.ListenAndServe(*httpAddr, nil))
 }